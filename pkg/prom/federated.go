@@ -0,0 +1,190 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/kubecost/cost-model/pkg/log"
+)
+
+// ClusterIDLabel and ProviderLabel are the metric labels FederatedClient
+// injects into every QueryResult it merges, so that downstream callers
+// (GetPVInfo, GetPodLabelsMetrics, etc.) can key by cluster without a
+// Thanos/Cortex deployment in front of Prometheus.
+const (
+	ClusterIDLabel = "cluster_id"
+	ProviderLabel  = "provider"
+)
+
+// FailurePolicy controls how FederatedClient.Query reacts when one of its
+// endpoints errors.
+type FailurePolicy int
+
+const (
+	// FailFast aborts the whole federated query as soon as any endpoint
+	// errors.
+	FailFast FailurePolicy = iota
+	// BestEffort returns merged results from whichever endpoints succeeded,
+	// recording the rest in QueryResults.PartialErrors.
+	BestEffort
+)
+
+// Client is the minimal interface a per-cluster Prometheus client must
+// satisfy to be registered with a FederatedClient. It returns the same raw,
+// decoded-JSON shape that NewQueryResults accepts.
+type Client interface {
+	Query(ctx context.Context, query string) (interface{}, error)
+}
+
+// ClusterEndpoint registers a single cluster's Prometheus (or
+// Prometheus-compatible) endpoint with a FederatedClient.
+type ClusterEndpoint struct {
+	// ClusterID is injected as the ClusterIDLabel on every QueryResult this
+	// endpoint returns.
+	ClusterID string
+	// Provider is injected as the ProviderLabel on every QueryResult this
+	// endpoint returns, if non-empty.
+	Provider string
+	Client   Client
+	Timeout  time.Duration
+}
+
+// FederatedClient executes a single PromQL query concurrently against a set
+// of registered cluster endpoints and merges the results into one
+// *QueryResults, tagging each QueryResult's Metric with the cluster it came
+// from.
+type FederatedClient struct {
+	Endpoints     []ClusterEndpoint
+	FailurePolicy FailurePolicy
+}
+
+// NewFederatedClient returns a FederatedClient that fans a query out across
+// endpoints according to policy.
+func NewFederatedClient(endpoints []ClusterEndpoint, policy FailurePolicy) *FederatedClient {
+	return &FederatedClient{
+		Endpoints:     endpoints,
+		FailurePolicy: policy,
+	}
+}
+
+// endpointResult is the outcome of querying a single ClusterEndpoint.
+type endpointResult struct {
+	endpoint ClusterEndpoint
+	qrs      *QueryResults
+	err      error
+}
+
+// Query runs query against every registered endpoint concurrently, tags
+// each resulting QueryResult's Metric with the endpoint's cluster_id (and
+// provider, if set), and merges everything into a single *QueryResults.
+//
+// Under FailFast, the first endpoint error is returned immediately (other
+// in-flight queries are abandoned via ctx cancellation). Under BestEffort,
+// every endpoint is allowed to finish; failures are collected into the
+// returned QueryResults.PartialErrors instead of aborting the merge.
+func (fc *FederatedClient) Query(ctx context.Context, query string) (*QueryResults, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	resultsCh := make(chan endpointResult, len(fc.Endpoints))
+
+	var wg sync.WaitGroup
+	for _, ep := range fc.Endpoints {
+		ep := ep
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+
+			qCtx := ctx
+			var qCancel context.CancelFunc
+			if ep.Timeout > 0 {
+				qCtx, qCancel = context.WithTimeout(ctx, ep.Timeout)
+				defer qCancel()
+			}
+
+			raw, err := ep.Client.Query(qCtx, query)
+			if err != nil {
+				resultsCh <- endpointResult{endpoint: ep, err: fmt.Errorf("cluster %q: %w", ep.ClusterID, err)}
+				return
+			}
+
+			qrs, err := NewQueryResults(query, raw)
+			if err != nil {
+				resultsCh <- endpointResult{endpoint: ep, err: fmt.Errorf("cluster %q: %w", ep.ClusterID, err)}
+				return
+			}
+
+			resultsCh <- endpointResult{endpoint: ep, qrs: qrs}
+		}()
+	}
+
+	go func() {
+		wg.Wait()
+		close(resultsCh)
+	}()
+
+	merged := &QueryResults{Query: query}
+	for res := range resultsCh {
+		if res.err != nil {
+			if fc.FailurePolicy == FailFast {
+				cancel()
+				return nil, res.err
+			}
+
+			log.Warningf("federated query %q: %s", query, res.err)
+			merged.PartialErrors = append(merged.PartialErrors, res.err)
+			continue
+		}
+
+		// "scalar"/"string" results carry no series labels, so there's
+		// nothing to tag with cluster_id and no well-defined way to merge
+		// one cluster's single value with another's. Rather than silently
+		// dropping the data, surface it as a partial error (or fail fast).
+		if res.qrs.ResultType == "scalar" || res.qrs.ResultType == "string" {
+			err := fmt.Errorf("cluster %q: federated queries do not support resultType %q", res.endpoint.ClusterID, res.qrs.ResultType)
+			if fc.FailurePolicy == FailFast {
+				cancel()
+				return nil, err
+			}
+
+			log.Warningf("federated query %q: %s", query, err)
+			merged.PartialErrors = append(merged.PartialErrors, err)
+			continue
+		}
+
+		if merged.ResultType == "" {
+			merged.ResultType = res.qrs.ResultType
+		} else if res.qrs.ResultType != merged.ResultType {
+			err := fmt.Errorf("cluster %q: resultType %q does not match %q from an earlier endpoint", res.endpoint.ClusterID, res.qrs.ResultType, merged.ResultType)
+			if fc.FailurePolicy == FailFast {
+				cancel()
+				return nil, err
+			}
+
+			log.Warningf("federated query %q: %s", query, err)
+			merged.PartialErrors = append(merged.PartialErrors, err)
+			continue
+		}
+
+		tagClusterID(res.qrs, res.endpoint)
+		merged.Results = append(merged.Results, res.qrs.Results...)
+	}
+
+	return merged, nil
+}
+
+// tagClusterID injects ClusterIDLabel and, if set, ProviderLabel into every
+// QueryResult's Metric map so callers can key by cluster after the merge.
+func tagClusterID(qrs *QueryResults, ep ClusterEndpoint) {
+	for _, result := range qrs.Results {
+		if result.Metric == nil {
+			result.Metric = map[string]interface{}{}
+		}
+		result.Metric[ClusterIDLabel] = ep.ClusterID
+		if ep.Provider != "" {
+			result.Metric[ProviderLabel] = ep.Provider
+		}
+	}
+}
@@ -0,0 +1,112 @@
+package prom
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+type fakeClient struct {
+	raw interface{}
+	err error
+}
+
+func (f fakeClient) Query(ctx context.Context, query string) (interface{}, error) {
+	return f.raw, f.err
+}
+
+func vectorRaw(pod string, value string) interface{} {
+	return map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"resultType": "vector",
+			"result": []interface{}{
+				map[string]interface{}{
+					"metric": map[string]interface{}{"pod": pod},
+					"value":  []interface{}{float64(100), value},
+				},
+			},
+		},
+	}
+}
+
+func scalarRaw(value string) interface{} {
+	return map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"resultType": "scalar",
+			"result":     []interface{}{float64(100), value},
+		},
+	}
+}
+
+func TestFederatedClientTagsClusterID(t *testing.T) {
+	fc := NewFederatedClient([]ClusterEndpoint{
+		{ClusterID: "cluster-a", Client: fakeClient{raw: vectorRaw("a", "1")}},
+		{ClusterID: "cluster-b", Client: fakeClient{raw: vectorRaw("b", "2")}},
+	}, BestEffort)
+
+	qrs, err := fc.Query(context.Background(), "up")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(qrs.Results) != 2 {
+		t.Fatalf("expected 2 merged results, got %d", len(qrs.Results))
+	}
+
+	seen := map[string]bool{}
+	for _, result := range qrs.Results {
+		cid, _ := result.Metric[ClusterIDLabel].(string)
+		seen[cid] = true
+	}
+	if !seen["cluster-a"] || !seen["cluster-b"] {
+		t.Fatalf("expected both cluster ids tagged, got %v", seen)
+	}
+}
+
+func TestFederatedClientFailFastAbortsOnError(t *testing.T) {
+	fc := NewFederatedClient([]ClusterEndpoint{
+		{ClusterID: "cluster-a", Client: fakeClient{raw: vectorRaw("a", "1")}},
+		{ClusterID: "cluster-b", Client: fakeClient{err: errors.New("boom")}},
+	}, FailFast)
+
+	if _, err := fc.Query(context.Background(), "up"); err == nil {
+		t.Fatal("expected an error under FailFast")
+	}
+}
+
+func TestFederatedClientBestEffortCollectsPartialErrors(t *testing.T) {
+	fc := NewFederatedClient([]ClusterEndpoint{
+		{ClusterID: "cluster-a", Client: fakeClient{raw: vectorRaw("a", "1")}},
+		{ClusterID: "cluster-b", Client: fakeClient{err: errors.New("boom")}},
+	}, BestEffort)
+
+	qrs, err := fc.Query(context.Background(), "up")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(qrs.Results) != 1 {
+		t.Fatalf("expected 1 surviving result, got %d", len(qrs.Results))
+	}
+	if len(qrs.PartialErrors) != 1 {
+		t.Fatalf("expected 1 partial error, got %d", len(qrs.PartialErrors))
+	}
+}
+
+func TestFederatedClientRejectsScalarInsteadOfDroppingIt(t *testing.T) {
+	fc := NewFederatedClient([]ClusterEndpoint{
+		{ClusterID: "cluster-a", Client: fakeClient{raw: vectorRaw("a", "1")}},
+		{ClusterID: "cluster-b", Client: fakeClient{raw: scalarRaw("1")}},
+	}, BestEffort)
+
+	qrs, err := fc.Query(context.Background(), "up")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(qrs.Results) != 1 {
+		t.Fatalf("expected only the vector endpoint's result to merge, got %d", len(qrs.Results))
+	}
+	if len(qrs.PartialErrors) != 1 {
+		t.Fatalf("expected the scalar endpoint to be recorded as a partial error, got %d", len(qrs.PartialErrors))
+	}
+}
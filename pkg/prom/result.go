@@ -4,6 +4,7 @@ import (
 	"errors"
 	"fmt"
 	"math"
+	"sort"
 	"strconv"
 	"strings"
 
@@ -48,10 +49,37 @@ type QueryResult struct {
 	Values []*util.Vector
 }
 
+// ScalarResult represents a prometheus "scalar" result: a single instant
+// [timestamp, value] pair with no associated series or metric labels.
+type ScalarResult struct {
+	Timestamp float64
+	Value     float64
+}
+
+// StringResult represents a prometheus "string" result: a single instant
+// [timestamp, value] pair whose value is a string rather than a float.
+type StringResult struct {
+	Timestamp float64
+	Value     string
+}
+
 // QueryResults contains all of the query results and the source query string.
+// ResultType records which of prometheus's four result types ("vector",
+// "matrix", "scalar", "string") the query produced. Results is populated for
+// "vector" and "matrix"; Scalar and String are populated for their
+// respective result types and are nil otherwise.
 type QueryResults struct {
-	Query   string
-	Results []*QueryResult
+	Query      string
+	ResultType string
+	Results    []*QueryResult
+	Scalar     *ScalarResult
+	String     *StringResult
+
+	// PartialErrors holds per-source errors for a QueryResults assembled
+	// from more than one backend (see FederatedClient) that was run with a
+	// best-effort failure policy. It is nil for a QueryResults from a single
+	// Prometheus response.
+	PartialErrors []error
 }
 
 // NewQueryResults accepts the raw prometheus query result and returns an array of
@@ -79,86 +107,185 @@ func NewQueryResults(query string, queryResult interface{}) (*QueryResults, erro
 	if !ok {
 		return nil, ResultFieldDoesNotExistErr
 	}
-	resultsData, ok := resultData.([]interface{})
-	if !ok {
-		return nil, ResultFieldFormatErr
-	}
 
-	// Result vectors from the query
-	var results []*QueryResult
+	// resultType tells us how to interpret resultData below. Its absence is
+	// tolerated for older/mocked responses and treated as "vector"/"matrix",
+	// which is distinguished per-series by the presence of "values".
+	resultType, _ := d["resultType"].(string)
 
-	// Parse raw results and into QueryResults
-	for _, val := range resultsData {
-		resultInterface, ok := val.(map[string]interface{})
-		if !ok {
-			return nil, ResultFormatErr
+	switch resultType {
+	case "scalar":
+		scalar, err := parseScalarResult(query, resultData)
+		if err != nil {
+			return nil, err
 		}
+		return &QueryResults{Query: query, ResultType: resultType, Scalar: scalar}, nil
 
-		metricInterface, ok := resultInterface["metric"]
-		if !ok {
-			return nil, MetricFieldDoesNotExistErr
+	case "string":
+		str, err := parseStringResult(resultData)
+		if err != nil {
+			return nil, err
 		}
-		metricMap, ok := metricInterface.(map[string]interface{})
+		return &QueryResults{Query: query, ResultType: resultType, String: str}, nil
+
+	case "", "vector", "matrix":
+		resultsData, ok := resultData.([]interface{})
 		if !ok {
-			return nil, MetricFieldFormatErr
+			return nil, ResultFieldFormatErr
 		}
 
-		// Define label string for values to ensure that we only run labelsForMetric once
-		// if we receive multiple warnings.
-		var labelString string = ""
-
-		// Determine if the result is a ranged data set or single value
-		_, isRange := resultInterface["values"]
+		// Result vectors from the query
+		var results []*QueryResult
 
-		var vectors []*util.Vector
-		if !isRange {
-			dataPoint, ok := resultInterface["value"]
+		// Parse raw results and into QueryResults
+		for _, val := range resultsData {
+			resultInterface, ok := val.(map[string]interface{})
 			if !ok {
-				return nil, ValueFieldDoesNotExistErr
+				return nil, ResultFormatErr
 			}
 
-			// Append new data point, log warnings
-			v, warn, err := parseDataPoint(dataPoint)
+			result, err := parseQueryResult(query, resultInterface)
 			if err != nil {
 				return nil, err
 			}
-			if warn != nil {
-				log.Warningf("%s\nQuery: %s\nLabels: %s", warn.Message(), query, labelsForMetric(metricMap))
-			}
 
-			vectors = append(vectors, v)
-		} else {
-			values, ok := resultInterface["values"].([]interface{})
-			if !ok {
-				return nil, ValuesFieldFormatErr
-			}
+			results = append(results, result)
+		}
 
-			// Append new data points, log warnings
-			for _, value := range values {
-				v, warn, err := parseDataPoint(value)
-				if err != nil {
-					return nil, err
-				}
-				if warn != nil {
-					if labelString == "" {
-						labelString = labelsForMetric(metricMap)
-					}
-					log.Warningf("%s\nQuery: %s\nLabels: %s", warn.Message(), query, labelString)
-				}
+		return &QueryResults{
+			Query:      query,
+			ResultType: resultType,
+			Results:    results,
+		}, nil
 
-				vectors = append(vectors, v)
-			}
+	default:
+		return nil, fmt.Errorf("unsupported prometheus resultType %q", resultType)
+	}
+}
+
+// parseScalarResult parses the [timestamp, value] pair prometheus returns
+// for a "scalar" resultType.
+func parseScalarResult(query string, resultData interface{}) (*ScalarResult, error) {
+	pair, ok := resultData.([]interface{})
+	if !ok || len(pair) != 2 {
+		return nil, ResultFieldFormatErr
+	}
+
+	ts, ok := pair[0].(float64)
+	if !ok {
+		return nil, ResultFieldFormatErr
+	}
+
+	strVal, ok := pair[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", DataPointFormatErr, pair[1])
+	}
+
+	v, err := strconv.ParseFloat(strVal, 64)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %q", DataPointFormatErr, strVal)
+	}
+
+	// Mirror parseDataPoint's Inf/NaN handling: a scalar query that
+	// legitimately divides by zero (e.g. scalar(a/b)) shouldn't silently
+	// hand callers a raw Inf/NaN.
+	if math.IsInf(v, 0) {
+		log.Warningf("%s\nQuery: %s", InfWarning.Message(), query)
+		v = 0.0
+	} else if math.IsNaN(v) {
+		log.Warningf("%s\nQuery: %s", NaNWarning.Message(), query)
+		v = 0.0
+	}
+
+	return &ScalarResult{Timestamp: ts, Value: v}, nil
+}
+
+// parseStringResult parses the [timestamp, value] pair prometheus returns
+// for a "string" resultType.
+func parseStringResult(resultData interface{}) (*StringResult, error) {
+	pair, ok := resultData.([]interface{})
+	if !ok || len(pair) != 2 {
+		return nil, ResultFieldFormatErr
+	}
+
+	ts, ok := pair[0].(float64)
+	if !ok {
+		return nil, ResultFieldFormatErr
+	}
+
+	strVal, ok := pair[1].(string)
+	if !ok {
+		return nil, fmt.Errorf("%w: %v", DataPointFormatErr, pair[1])
+	}
+
+	return &StringResult{Timestamp: ts, Value: strVal}, nil
+}
+
+// parseQueryResult converts a single decoded "result" entry from a prometheus
+// response (one series, either a single "value" or a ranged "values" array)
+// into a *QueryResult. It is shared by NewQueryResults and the streaming
+// decoder in NewQueryResultsFromReader so that both paths parse data points
+// identically.
+func parseQueryResult(query string, resultInterface map[string]interface{}) (*QueryResult, error) {
+	metricInterface, ok := resultInterface["metric"]
+	if !ok {
+		return nil, MetricFieldDoesNotExistErr
+	}
+	metricMap, ok := metricInterface.(map[string]interface{})
+	if !ok {
+		return nil, MetricFieldFormatErr
+	}
+
+	// Define label string for values to ensure that we only run labelsForMetric once
+	// if we receive multiple warnings.
+	var labelString string = ""
+
+	// Determine if the result is a ranged data set or single value
+	_, isRange := resultInterface["values"]
+
+	var vectors []*util.Vector
+	if !isRange {
+		dataPoint, ok := resultInterface["value"]
+		if !ok {
+			return nil, ValueFieldDoesNotExistErr
 		}
 
-		results = append(results, &QueryResult{
-			Metric: metricMap,
-			Values: vectors,
-		})
+		// Append new data point, log warnings
+		v, warn, err := parseDataPoint(dataPoint)
+		if err != nil {
+			return nil, err
+		}
+		if warn != nil {
+			log.Warningf("%s\nQuery: %s\nLabels: %s", warn.Message(), query, labelsForMetric(metricMap))
+		}
+
+		vectors = append(vectors, v)
+	} else {
+		values, ok := resultInterface["values"].([]interface{})
+		if !ok {
+			return nil, ValuesFieldFormatErr
+		}
+
+		// Append new data points, log warnings
+		for _, value := range values {
+			v, warn, err := parseDataPoint(value)
+			if err != nil {
+				return nil, err
+			}
+			if warn != nil {
+				if labelString == "" {
+					labelString = labelsForMetric(metricMap)
+				}
+				log.Warningf("%s\nQuery: %s\nLabels: %s", warn.Message(), query, labelString)
+			}
+
+			vectors = append(vectors, v)
+		}
 	}
 
-	return &QueryResults{
-		Query:   query,
-		Results: results,
+	return &QueryResult{
+		Metric: metricMap,
+		Values: vectors,
 	}, nil
 }
 
@@ -222,10 +349,22 @@ func parseDataPoint(dataPoint interface{}) (*util.Vector, warning, error) {
 		return nil, w, DataPointFormatErr
 	}
 
-	strVal := value[1].(string)
+	ts, ok := value[0].(float64)
+	if !ok {
+		return nil, w, fmt.Errorf("%w: timestamp %v", DataPointFormatErr, value[0])
+	}
+
+	strVal, ok := value[1].(string)
+	if !ok {
+		return nil, w, fmt.Errorf("%w: %v", DataPointFormatErr, value[1])
+	}
+
+	// strconv.ParseFloat natively handles the special encodings prometheus
+	// emits as strings ("NaN", "+Inf", "-Inf", "1.5e+10"); anything else
+	// malformed comes back as a DataPointFormatErr naming the bad value.
 	v, err := strconv.ParseFloat(strVal, 64)
 	if err != nil {
-		return nil, w, err
+		return nil, w, fmt.Errorf("%w: %q", DataPointFormatErr, strVal)
 	}
 
 	// Test for +Inf and -Inf (sign: 0), Test for NaN
@@ -238,7 +377,7 @@ func parseDataPoint(dataPoint interface{}) (*util.Vector, warning, error) {
 	}
 
 	return &util.Vector{
-		Timestamp: math.Round(value[0].(float64)/10) * 10,
+		Timestamp: math.Round(ts/10) * 10,
 		Value:     v,
 	}, w, nil
 }
@@ -252,6 +391,28 @@ func labelsForMetric(metricMap map[string]interface{}) string {
 	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
 }
 
+// canonicalMetricKey returns a deterministic string representation of a
+// metric's labels, suitable for use as a map key when matching series
+// across independently-decoded QueryResults (e.g. in the Validator). Unlike
+// labelsForMetric, which is for human-readable logging and walks the map in
+// Go's unspecified iteration order, this sorts keys first so two metrics
+// with identical labels always produce the same key regardless of how their
+// maps were built.
+func canonicalMetricKey(metricMap map[string]interface{}) string {
+	keys := make([]string, 0, len(metricMap))
+	for k := range metricMap {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	pairs := make([]string, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, fmt.Sprintf("%s: %+v", k, metricMap[k]))
+	}
+
+	return fmt.Sprintf("{%s}", strings.Join(pairs, ", "))
+}
+
 func wrapPrometheusError(qr interface{}) (string, error) {
 	e, ok := qr.(map[string]interface{})["error"]
 	if !ok {
@@ -0,0 +1,134 @@
+package prom
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func rawVectorResponse() interface{} {
+	return map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"resultType": "vector",
+			"result": []interface{}{
+				map[string]interface{}{
+					"metric": map[string]interface{}{"pod": "a"},
+					"value":  []interface{}{float64(100), "1.5"},
+				},
+			},
+		},
+	}
+}
+
+func TestNewQueryResultsVector(t *testing.T) {
+	qrs, err := NewQueryResults("up", rawVectorResponse())
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if qrs.ResultType != "vector" {
+		t.Fatalf("expected resultType vector, got %q", qrs.ResultType)
+	}
+	if len(qrs.Results) != 1 || len(qrs.Results[0].Values) != 1 {
+		t.Fatalf("unexpected results: %+v", qrs.Results)
+	}
+	if qrs.Results[0].Values[0].Value != 1.5 {
+		t.Fatalf("expected value 1.5, got %v", qrs.Results[0].Values[0].Value)
+	}
+}
+
+func TestNewQueryResultsScalar(t *testing.T) {
+	raw := map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"resultType": "scalar",
+			"result":     []interface{}{float64(100), "42"},
+		},
+	}
+
+	qrs, err := NewQueryResults("up", raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if qrs.Scalar == nil || qrs.Scalar.Value != 42 {
+		t.Fatalf("expected scalar value 42, got %+v", qrs.Scalar)
+	}
+	if len(qrs.Results) != 0 {
+		t.Fatalf("expected no per-series Results for a scalar response, got %d", len(qrs.Results))
+	}
+}
+
+func TestNewQueryResultsScalarNormalizesInfAndNaN(t *testing.T) {
+	for _, strVal := range []string{"+Inf", "-Inf", "NaN"} {
+		raw := map[string]interface{}{
+			"status": "success",
+			"data": map[string]interface{}{
+				"resultType": "scalar",
+				"result":     []interface{}{float64(100), strVal},
+			},
+		}
+
+		qrs, err := NewQueryResults("up", raw)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %s", strVal, err)
+		}
+		if qrs.Scalar == nil || qrs.Scalar.Value != 0 {
+			t.Fatalf("%s: expected a scalar divide-by-zero to normalize to 0, got %+v", strVal, qrs.Scalar)
+		}
+	}
+}
+
+func TestNewQueryResultsString(t *testing.T) {
+	raw := map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"resultType": "string",
+			"result":     []interface{}{float64(100), "hello"},
+		},
+	}
+
+	qrs, err := NewQueryResults("up", raw)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if qrs.String == nil || qrs.String.Value != "hello" {
+		t.Fatalf("expected string value \"hello\", got %+v", qrs.String)
+	}
+}
+
+func TestNewQueryResultsMalformedDataPointDoesNotPanic(t *testing.T) {
+	raw := map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"resultType": "vector",
+			"result": []interface{}{
+				map[string]interface{}{
+					"metric": map[string]interface{}{"pod": "a"},
+					"value":  []interface{}{float64(100), "not-a-float"},
+				},
+			},
+		},
+	}
+
+	_, err := NewQueryResults("up", raw)
+	if !errors.Is(err, DataPointFormatErr) {
+		t.Fatalf("expected a DataPointFormatErr, got %v", err)
+	}
+	if !strings.Contains(err.Error(), "not-a-float") {
+		t.Fatalf("expected the error to name the offending value, got %q", err.Error())
+	}
+}
+
+func TestNewQueryResultsUnsupportedResultType(t *testing.T) {
+	raw := map[string]interface{}{
+		"status": "success",
+		"data": map[string]interface{}{
+			"resultType": "bogus",
+			"result":     []interface{}{},
+		},
+	}
+
+	if _, err := NewQueryResults("up", raw); err == nil {
+		t.Fatal("expected an error for an unsupported resultType")
+	}
+}
@@ -0,0 +1,255 @@
+package prom
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Static Errors for selector parsing
+var (
+	SelectorFormatErr error = fmt.Errorf("improperly formatted label selector")
+)
+
+// selectorOp is the comparison a single selector requirement applies to a
+// metric label.
+type selectorOp string
+
+const (
+	selectorOpEquals    selectorOp = "="
+	selectorOpNotEquals selectorOp = "!="
+	selectorOpIn        selectorOp = "in"
+	selectorOpNotIn     selectorOp = "notin"
+	selectorOpExists    selectorOp = "exists"
+	selectorOpNotExists selectorOp = "!exists"
+)
+
+// requirement is a single term of a Selector, e.g. `app=foo` or
+// `env in (prod,stage)`.
+type requirement struct {
+	key    string
+	op     selectorOp
+	values []string
+}
+
+func (r requirement) matches(metric map[string]interface{}) bool {
+	raw, ok := metric[r.key]
+	value, _ := raw.(string)
+
+	switch r.op {
+	case selectorOpExists:
+		return ok
+	case selectorOpNotExists:
+		return !ok
+	case selectorOpEquals:
+		return ok && value == r.values[0]
+	case selectorOpNotEquals:
+		return !ok || value != r.values[0]
+	case selectorOpIn:
+		if !ok {
+			return false
+		}
+		for _, v := range r.values {
+			if value == v {
+				return true
+			}
+		}
+		return false
+	case selectorOpNotIn:
+		if !ok {
+			return true
+		}
+		for _, v := range r.values {
+			if value == v {
+				return false
+			}
+		}
+		return true
+	default:
+		return false
+	}
+}
+
+// Selector is a parsed Kubernetes-style label selector (e.g.
+// `app=foo,tier!=db,env in (prod,stage)`) that can be matched against the
+// Metric map of a QueryResult.
+type Selector struct {
+	requirements []requirement
+}
+
+// Empty reports whether the selector has no requirements, i.e. it matches
+// every metric.
+func (s Selector) Empty() bool {
+	return len(s.requirements) == 0
+}
+
+// Matches reports whether every requirement in the selector is satisfied by
+// the given metric labels.
+func (s Selector) Matches(metric map[string]interface{}) bool {
+	for _, r := range s.requirements {
+		if !r.matches(metric) {
+			return false
+		}
+	}
+	return true
+}
+
+// ParseSelector parses a Kubernetes-style label selector string, supporting
+// `=`, `==`, `!=`, `in (...)`, `notin (...)`, `key`, and `!key` (exists and
+// does-not-exist) requirements joined by commas. It mirrors the semantics of
+// k8s.io/apimachinery/pkg/labels.Parse closely enough for filtering
+// QueryResult metrics, without taking on the apimachinery dependency.
+func ParseSelector(selector string) (Selector, error) {
+	selector = strings.TrimSpace(selector)
+	if selector == "" {
+		return Selector{}, nil
+	}
+
+	var reqs []requirement
+	for _, term := range splitSelectorTerms(selector) {
+		term = strings.TrimSpace(term)
+		if term == "" {
+			continue
+		}
+
+		req, err := parseRequirement(term)
+		if err != nil {
+			return Selector{}, err
+		}
+		reqs = append(reqs, req)
+	}
+
+	return Selector{requirements: reqs}, nil
+}
+
+// splitSelectorTerms splits a selector string on top-level commas, ignoring
+// commas inside an `in (...)`/`notin (...)` value list.
+func splitSelectorTerms(selector string) []string {
+	var terms []string
+	depth := 0
+	start := 0
+
+	for i, r := range selector {
+		switch r {
+		case '(':
+			depth++
+		case ')':
+			depth--
+		case ',':
+			if depth == 0 {
+				terms = append(terms, selector[start:i])
+				start = i + 1
+			}
+		}
+	}
+	terms = append(terms, selector[start:])
+
+	return terms
+}
+
+func parseRequirement(term string) (requirement, error) {
+	if strings.HasPrefix(term, "!") {
+		key := strings.TrimSpace(term[1:])
+		if key == "" {
+			return requirement{}, SelectorFormatErr
+		}
+		return requirement{key: key, op: selectorOpNotExists}, nil
+	}
+
+	if idx := strings.Index(term, "!="); idx >= 0 {
+		return requirement{
+			key:    strings.TrimSpace(term[:idx]),
+			op:     selectorOpNotEquals,
+			values: []string{strings.TrimSpace(term[idx+2:])},
+		}, nil
+	}
+
+	if idx := strings.Index(term, "=="); idx >= 0 {
+		return requirement{
+			key:    strings.TrimSpace(term[:idx]),
+			op:     selectorOpEquals,
+			values: []string{strings.TrimSpace(term[idx+2:])},
+		}, nil
+	}
+
+	if idx := strings.Index(term, "="); idx >= 0 {
+		return requirement{
+			key:    strings.TrimSpace(term[:idx]),
+			op:     selectorOpEquals,
+			values: []string{strings.TrimSpace(term[idx+1:])},
+		}, nil
+	}
+
+	if key, list, ok := splitSetOp(term, " in "); ok {
+		return requirement{key: key, op: selectorOpIn, values: list}, nil
+	}
+
+	if key, list, ok := splitSetOp(term, " notin "); ok {
+		return requirement{key: key, op: selectorOpNotIn, values: list}, nil
+	}
+
+	key := strings.TrimSpace(term)
+	if key == "" {
+		return requirement{}, SelectorFormatErr
+	}
+	return requirement{key: key, op: selectorOpExists}, nil
+}
+
+// splitSetOp parses `key <op> (v1,v2,...)` terms for the "in"/"notin" ops.
+func splitSetOp(term string, op string) (string, []string, bool) {
+	idx := strings.Index(term, op)
+	if idx < 0 {
+		return "", nil, false
+	}
+
+	key := strings.TrimSpace(term[:idx])
+	rest := strings.TrimSpace(term[idx+len(op):])
+	if !strings.HasPrefix(rest, "(") || !strings.HasSuffix(rest, ")") {
+		return "", nil, false
+	}
+
+	rest = strings.TrimSuffix(strings.TrimPrefix(rest, "("), ")")
+	var values []string
+	for _, v := range strings.Split(rest, ",") {
+		values = append(values, strings.TrimSpace(v))
+	}
+
+	return key, values, true
+}
+
+// Matches reports whether the result's metric labels satisfy selector.
+func (qr *QueryResult) Matches(selector Selector) bool {
+	return selector.Matches(qr.Metric)
+}
+
+// Filter parses selector and returns a new *QueryResults containing only the
+// results whose metric labels satisfy it. It lets callers that already hold
+// a broad result set (e.g. reused across many namespace/cluster splits)
+// post-process in memory instead of re-issuing a narrower PromQL query.
+//
+// It only applies to "vector"/"matrix" QueryResults, since "scalar"/"string"
+// results have no per-series Metric map to filter on.
+func (qrs *QueryResults) Filter(selector string) (*QueryResults, error) {
+	if qrs.ResultType == "scalar" || qrs.ResultType == "string" {
+		return nil, fmt.Errorf("label selector filtering does not apply to resultType %q, which has no series labels", qrs.ResultType)
+	}
+
+	sel, err := ParseSelector(selector)
+	if err != nil {
+		return nil, err
+	}
+
+	filtered := &QueryResults{
+		Query:         qrs.Query,
+		ResultType:    qrs.ResultType,
+		Scalar:        qrs.Scalar,
+		String:        qrs.String,
+		PartialErrors: qrs.PartialErrors,
+	}
+	for _, result := range qrs.Results {
+		if result.Matches(sel) {
+			filtered.Results = append(filtered.Results, result)
+		}
+	}
+
+	return filtered, nil
+}
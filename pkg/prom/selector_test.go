@@ -0,0 +1,61 @@
+package prom
+
+import "testing"
+
+func TestParseSelectorAndMatches(t *testing.T) {
+	cases := []struct {
+		name     string
+		selector string
+		metric   map[string]interface{}
+		want     bool
+	}{
+		{"equals match", "app=foo", map[string]interface{}{"app": "foo"}, true},
+		{"equals mismatch", "app=foo", map[string]interface{}{"app": "bar"}, false},
+		{"not-equals", "tier!=db", map[string]interface{}{"tier": "web"}, true},
+		{"in", "env in (prod,stage)", map[string]interface{}{"env": "stage"}, true},
+		{"notin", "env notin (prod,stage)", map[string]interface{}{"env": "dev"}, true},
+		{"exists", "app", map[string]interface{}{"app": "foo"}, true},
+		{"not-exists", "!app", map[string]interface{}{"other": "x"}, true},
+		{"multi match", "app=foo,env in (prod,stage)", map[string]interface{}{"app": "foo", "env": "prod"}, true},
+		{"multi mismatch", "app=foo,env in (prod,stage)", map[string]interface{}{"app": "foo", "env": "dev"}, false},
+	}
+
+	for _, c := range cases {
+		t.Run(c.name, func(t *testing.T) {
+			sel, err := ParseSelector(c.selector)
+			if err != nil {
+				t.Fatalf("ParseSelector(%q): %s", c.selector, err)
+			}
+			if got := sel.Matches(c.metric); got != c.want {
+				t.Fatalf("Matches(%v) = %v, want %v", c.metric, got, c.want)
+			}
+		})
+	}
+}
+
+func TestFilter(t *testing.T) {
+	qrs := &QueryResults{
+		Query: "up",
+		Results: []*QueryResult{
+			{Metric: map[string]interface{}{"app": "foo"}},
+			{Metric: map[string]interface{}{"app": "bar"}},
+		},
+	}
+
+	filtered, err := qrs.Filter("app=foo")
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if len(filtered.Results) != 1 {
+		t.Fatalf("expected 1 filtered result, got %d", len(filtered.Results))
+	}
+}
+
+func TestFilterRejectsScalarAndString(t *testing.T) {
+	for _, resultType := range []string{"scalar", "string"} {
+		qrs := &QueryResults{ResultType: resultType}
+		if _, err := qrs.Filter("app=foo"); err == nil {
+			t.Fatalf("expected an error filtering a %q QueryResults", resultType)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package prom
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/kubecost/cost-model/pkg/log"
+)
+
+// QueryResultFunc is invoked once per QueryResult as it is decoded from a
+// streaming response. Returning an error aborts the decode.
+type QueryResultFunc func(*QueryResult) error
+
+// NewQueryResultsFromReader behaves like NewQueryResults, but consumes r
+// incrementally with a json.Decoder instead of unmarshaling the whole
+// response into a map[string]interface{} first. Range queries spanning
+// weeks of container/PV/label metrics otherwise build a huge nested
+// interface tree before a single result can be processed; for "vector"/
+// "matrix" resultTypes this path decodes the "data.result" array element by
+// element, handing each one to onResult (if non-nil) as soon as it is
+// parsed. "scalar" and "string" resultTypes have no per-series array to
+// stream, so they're decoded in one shot like NewQueryResults does.
+//
+// With retainResults=true the returned *QueryResults still aggregates every
+// parsed result, so callers that don't care about streaming can use it exactly
+// like NewQueryResults. Callers chasing the memory savings of a huge range
+// query should pass retainResults=false: onResult is still invoked per
+// result as it's decoded, but the QueryResult is dropped afterward instead
+// of being appended to Results, so peak memory stays bounded by one series
+// at a time rather than the whole response. With retainResults=false,
+// Results is nil and onResult must be non-nil or every parsed result is
+// simply discarded.
+func NewQueryResultsFromReader(query string, r io.Reader, onResult QueryResultFunc, retainResults bool) (*QueryResults, error) {
+	dec := json.NewDecoder(r)
+
+	resultType, err := scanToResultField(dec)
+	if err != nil {
+		return nil, err
+	}
+
+	switch resultType {
+	case "scalar":
+		var pair []interface{}
+		if err := dec.Decode(&pair); err != nil {
+			return nil, fmt.Errorf("decoding scalar result: %w", err)
+		}
+		scalar, err := parseScalarResult(query, pair)
+		if err != nil {
+			return nil, err
+		}
+		return &QueryResults{Query: query, ResultType: resultType, Scalar: scalar}, nil
+
+	case "string":
+		var pair []interface{}
+		if err := dec.Decode(&pair); err != nil {
+			return nil, fmt.Errorf("decoding string result: %w", err)
+		}
+		str, err := parseStringResult(pair)
+		if err != nil {
+			return nil, err
+		}
+		return &QueryResults{Query: query, ResultType: resultType, String: str}, nil
+
+	case "", "vector", "matrix":
+		if err := expectArrayStart(dec); err != nil {
+			return nil, err
+		}
+
+		var results []*QueryResult
+		for dec.More() {
+			var resultInterface map[string]interface{}
+			if err := dec.Decode(&resultInterface); err != nil {
+				return nil, fmt.Errorf("decoding result entry: %w", err)
+			}
+
+			result, err := parseQueryResult(query, resultInterface)
+			if err != nil {
+				return nil, err
+			}
+
+			if onResult != nil {
+				if err := onResult(result); err != nil {
+					return nil, err
+				}
+			}
+
+			if retainResults {
+				results = append(results, result)
+			}
+		}
+
+		// Consume the closing "]" of the result array.
+		if _, err := dec.Token(); err != nil {
+			return nil, fmt.Errorf("closing result array: %w", err)
+		}
+
+		return &QueryResults{
+			Query:      query,
+			ResultType: resultType,
+			Results:    results,
+		}, nil
+
+	default:
+		return nil, fmt.Errorf("unsupported prometheus resultType %q", resultType)
+	}
+}
+
+// QueryResultsChanFromReader runs NewQueryResultsFromReader in a goroutine
+// and delivers the aggregated *QueryResults on the returned channel, which
+// is buffered to bufferSize. With bufferSize >= 1 the producer's single send
+// never blocks waiting for a consumer that hasn't started reading yet; with
+// bufferSize 0 the send blocks until the consumer receives. onResult, if
+// non-nil, still
+// fires per-result as the body streams in, ahead of the channel send.
+func QueryResultsChanFromReader(query string, r io.Reader, bufferSize int, onResult QueryResultFunc) QueryResultsChan {
+	ch := make(QueryResultsChan, bufferSize)
+
+	go func() {
+		qrs, err := NewQueryResultsFromReader(query, r, onResult, true)
+		if err != nil {
+			log.Warningf("streaming query results for %q: %s", query, err)
+			qrs = &QueryResults{Query: query}
+		}
+		ch <- qrs
+	}()
+
+	return ch
+}
+
+// scanFrame tracks one open JSON object or array while scanToResultField
+// walks the token stream. Array elements are always values, so expectKey is
+// meaningless (and left false) for array frames; object frames toggle
+// expectKey between a key and its value.
+type scanFrame struct {
+	isArray   bool
+	expectKey bool
+}
+
+// scanToResultField advances dec past the "data":{...} prefix of a
+// prometheus response up to (but not including) the value of the "result"
+// field, returning the resultType recorded from the sibling "resultType"
+// field. Prometheus always emits "resultType" before "result", so by the
+// time "result" is reached resultType has already been captured.
+//
+// A prometheus error response (e.g. `{"status":"error","errorType":
+// "bad_data","error":"parse error at char 1"}`) has no "data"/"result" at
+// all, so the scan runs off the end of the object and hits io.EOF. Along the
+// way it also tracks the top-level "error" field so that case surfaces the
+// real server message instead of a bare EOF, mirroring how NewQueryResults
+// uses wrapPrometheusError for the same shape.
+func scanToResultField(dec *json.Decoder) (string, error) {
+	var frames []scanFrame
+	var resultType string
+	var errMsg string
+	haveErrMsg := false
+
+	for {
+		tok, err := dec.Token()
+		if err != nil {
+			if err == io.EOF {
+				if haveErrMsg {
+					return "", errors.New(errMsg)
+				}
+				return "", PromUnexpectedResponseErr
+			}
+			return "", fmt.Errorf("scanning for result field: %w", err)
+		}
+
+		if d, ok := tok.(json.Delim); ok {
+			switch d {
+			case '{':
+				frames = append(frames, scanFrame{expectKey: true})
+			case '[':
+				frames = append(frames, scanFrame{isArray: true})
+			case '}', ']':
+				frames = frames[:len(frames)-1]
+				markValueConsumed(frames)
+			}
+			continue
+		}
+
+		depth := len(frames)
+		top := depth > 0 && !frames[depth-1].isArray && frames[depth-1].expectKey
+		if !top {
+			// tok is a value (either we're at the top level, inside an
+			// array, or an object frame awaiting its value).
+			markValueConsumed(frames)
+			continue
+		}
+
+		// tok is an object key.
+		key, _ := tok.(string)
+
+		if depth == 1 && key == "error" {
+			val, err := dec.Token()
+			if err != nil {
+				return "", fmt.Errorf("reading error field: %w", err)
+			}
+			if s, ok := val.(string); ok {
+				errMsg = s
+				haveErrMsg = true
+			}
+			markValueConsumed(frames)
+			continue
+		}
+
+		if depth == 2 && key == "resultType" {
+			val, err := dec.Token()
+			if err != nil {
+				return "", fmt.Errorf("reading resultType: %w", err)
+			}
+			if s, ok := val.(string); ok {
+				resultType = s
+			}
+			markValueConsumed(frames)
+			continue
+		}
+
+		if depth == 2 && key == "result" {
+			return resultType, nil
+		}
+
+		// An uninteresting key: its value, read on the next loop iteration,
+		// will flip this frame back to expecting a key.
+		frames[depth-1].expectKey = false
+	}
+}
+
+// markValueConsumed flips the innermost object frame back to expecting a
+// key, used after a value (scalar or a just-closed nested container)
+// completes. Array frames never expect a key, so they're left untouched.
+func markValueConsumed(frames []scanFrame) {
+	if len(frames) == 0 {
+		return
+	}
+	top := &frames[len(frames)-1]
+	if !top.isArray {
+		top.expectKey = true
+	}
+}
+
+// expectArrayStart consumes the next token and errors unless it is the
+// opening "[" of a JSON array.
+func expectArrayStart(dec *json.Decoder) error {
+	tok, err := dec.Token()
+	if err != nil {
+		return fmt.Errorf("reading result array: %w", err)
+	}
+	if d, ok := tok.(json.Delim); !ok || d != '[' {
+		return ResultFieldFormatErr
+	}
+	return nil
+}
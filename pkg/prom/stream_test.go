@@ -0,0 +1,130 @@
+package prom
+
+import (
+	"encoding/json"
+	"strings"
+	"testing"
+)
+
+const vectorJSON = `{"status":"success","data":{"resultType":"vector","result":[` +
+	`{"metric":{"pod":"a"},"value":[100,"1.5"]},` +
+	`{"metric":{"pod":"b"},"value":[100,"2.5"]}` +
+	`]}}`
+
+func TestNewQueryResultsFromReaderMatchesMapBased(t *testing.T) {
+	var raw interface{}
+	if err := json.Unmarshal([]byte(vectorJSON), &raw); err != nil {
+		t.Fatalf("unmarshal: %s", err)
+	}
+
+	want, err := NewQueryResults("up", raw)
+	if err != nil {
+		t.Fatalf("NewQueryResults: %s", err)
+	}
+
+	var seen int
+	got, err := NewQueryResultsFromReader("up", strings.NewReader(vectorJSON), func(qr *QueryResult) error {
+		seen++
+		return nil
+	}, true)
+	if err != nil {
+		t.Fatalf("NewQueryResultsFromReader: %s", err)
+	}
+
+	if got.ResultType != want.ResultType {
+		t.Fatalf("resultType mismatch: got %q want %q", got.ResultType, want.ResultType)
+	}
+	if len(got.Results) != len(want.Results) {
+		t.Fatalf("result count mismatch: got %d want %d", len(got.Results), len(want.Results))
+	}
+	if seen != len(want.Results) {
+		t.Fatalf("onResult fired %d times, want %d", seen, len(want.Results))
+	}
+}
+
+func TestNewQueryResultsFromReaderScalar(t *testing.T) {
+	const scalarJSON = `{"status":"success","data":{"resultType":"scalar","result":[100,"42"]}}`
+
+	got, err := NewQueryResultsFromReader("up", strings.NewReader(scalarJSON), nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.ResultType != "scalar" || got.Scalar == nil || got.Scalar.Value != 42 {
+		t.Fatalf("unexpected result: %+v", got)
+	}
+}
+
+func TestNewQueryResultsFromReaderString(t *testing.T) {
+	const stringJSON = `{"status":"success","data":{"resultType":"string","result":[100,"hello"]}}`
+
+	got, err := NewQueryResultsFromReader("up", strings.NewReader(stringJSON), nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.ResultType != "string" || got.String == nil || got.String.Value != "hello" {
+		t.Fatalf("unexpected result: %+v", got)
+	}
+}
+
+func TestNewQueryResultsFromReaderSurfacesPrometheusError(t *testing.T) {
+	const errorJSON = `{"status":"error","errorType":"bad_data","error":"parse error at char 1"}`
+
+	_, err := NewQueryResultsFromReader("up", strings.NewReader(errorJSON), nil, true)
+	if err == nil {
+		t.Fatal("expected an error for a prometheus error response")
+	}
+	if !strings.Contains(err.Error(), "parse error at char 1") {
+		t.Fatalf("expected the real prometheus error message, got %q", err.Error())
+	}
+}
+
+// TestNewQueryResultsFromReaderSurfacesPrometheusErrorWithPercent guards
+// against treating the live prometheus error string as a fmt format string;
+// a literal "%" in the message must survive unmangled.
+func TestNewQueryResultsFromReaderSurfacesPrometheusErrorWithPercent(t *testing.T) {
+	const errorJSON = `{"status":"error","errorType":"bad_data","error":"parse error at char 1: unexpected character '%'"}`
+
+	_, err := NewQueryResultsFromReader("up", strings.NewReader(errorJSON), nil, true)
+	if err == nil {
+		t.Fatal("expected an error for a prometheus error response")
+	}
+	want := "parse error at char 1: unexpected character '%'"
+	if !strings.Contains(err.Error(), want) {
+		t.Fatalf("expected the real prometheus error message, got %q", err.Error())
+	}
+}
+
+// TestNewQueryResultsFromReaderSkipsWarningsArray guards against a
+// top-level array with >=2 elements (e.g. prometheus's documented
+// "warnings" field) before "data"/"result" being misread as key/value
+// pairs by the hand-rolled scanner.
+func TestNewQueryResultsFromReaderSkipsWarningsArray(t *testing.T) {
+	const withWarnings = `{"status":"success","warnings":["w1","result"],"data":{"resultType":"vector","result":[` +
+		`{"metric":{"pod":"a"},"value":[100,"1.5"]}` +
+		`]}}`
+
+	got, err := NewQueryResultsFromReader("up", strings.NewReader(withWarnings), nil, true)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.ResultType != "vector" || len(got.Results) != 1 {
+		t.Fatalf("unexpected result: %+v", got)
+	}
+}
+
+func TestNewQueryResultsFromReaderRetainResultsFalse(t *testing.T) {
+	var seen int
+	got, err := NewQueryResultsFromReader("up", strings.NewReader(vectorJSON), func(qr *QueryResult) error {
+		seen++
+		return nil
+	}, false)
+	if err != nil {
+		t.Fatalf("unexpected error: %s", err)
+	}
+	if got.Results != nil {
+		t.Fatalf("expected Results to be nil with retainResults=false, got %+v", got.Results)
+	}
+	if seen != 2 {
+		t.Fatalf("onResult fired %d times, want 2", seen)
+	}
+}
@@ -0,0 +1,154 @@
+package prom
+
+import (
+	"context"
+	"fmt"
+	"math"
+	"time"
+)
+
+// Querier issues a single PromQL query and returns its parsed results, e.g.
+// a thin wrapper around NewQueryResults(query, rawResponse). FederatedClient
+// satisfies it; a per-cluster Client, which returns the raw response, does
+// not.
+type Querier interface {
+	Query(ctx context.Context, query string) (*QueryResults, error)
+}
+
+// Expectation declares an invariant that should hold between two PromQL
+// queries: the series produced by Query must stay within Tolerance (a
+// relative error, e.g. 0.25 for ±25%) of the paired series produced by
+// Against. Window and Step describe the sampling cadence a caller should use
+// when invoking Validator.Run repeatedly (Run itself performs one sampling
+// pass per call).
+type Expectation struct {
+	Name      string
+	Query     string
+	Against   string
+	Tolerance float64
+	Window    time.Duration
+	Step      time.Duration
+}
+
+// SeriesReport describes one series that violated an Expectation's
+// tolerance during a Validator.Run pass.
+type SeriesReport struct {
+	Expectation   string
+	Labels        string
+	Timestamp     float64
+	Value         float64
+	AgainstValue  float64
+	RelativeError float64
+}
+
+// Report is the outcome of a Validator.Run pass: every series, across every
+// Expectation, whose relative error against its paired query exceeded
+// tolerance.
+type Report struct {
+	Failures []SeriesReport
+}
+
+// Failed reports whether the pass produced any failing series.
+func (r *Report) Failed() bool {
+	return len(r.Failures) > 0
+}
+
+// Validator runs a set of Expectations against a live Prometheus (via
+// Querier) and reports series whose paired queries diverge beyond
+// tolerance. It gives cost-model a self-check for the kube-state-metrics /
+// node-exporter drift that getNormalization's TODOs merely warn about.
+type Validator struct {
+	Querier      Querier
+	Expectations []Expectation
+}
+
+// NewValidator returns a Validator that checks expectations against q.
+func NewValidator(q Querier, expectations []Expectation) *Validator {
+	return &Validator{
+		Querier:      q,
+		Expectations: expectations,
+	}
+}
+
+// Run executes every Expectation's paired queries once, aligns their
+// samples by timestamp (results are already bucketed to the nearest 10s by
+// parseDataPoint, so aligned series share exact Timestamp values), and
+// returns a Report of series that fall outside tolerance. Callers that want
+// the "sampled every Step for Window" behavior described by an Expectation
+// should invoke Run on that cadence themselves.
+func (v *Validator) Run(ctx context.Context) (*Report, error) {
+	report := &Report{}
+
+	for _, exp := range v.Expectations {
+		qrs, err := v.Querier.Query(ctx, exp.Query)
+		if err != nil {
+			return nil, fmt.Errorf("expectation %q: querying %q: %w", exp.Name, exp.Query, err)
+		}
+
+		against, err := v.Querier.Query(ctx, exp.Against)
+		if err != nil {
+			return nil, fmt.Errorf("expectation %q: querying %q: %w", exp.Name, exp.Against, err)
+		}
+
+		report.Failures = append(report.Failures, compareResults(exp, qrs, against)...)
+	}
+
+	return report, nil
+}
+
+// compareResults pairs up qrs and against by a canonical (order-independent)
+// metric label key and reports any timestamp-aligned sample pair whose
+// relative error exceeds exp.Tolerance. qrs and against come from two
+// separate NewQueryResults calls, so the join key must not depend on Go's
+// unspecified map iteration order the way labelsForMetric does.
+func compareResults(exp Expectation, qrs, against *QueryResults) []SeriesReport {
+	againstByLabels := map[string]*QueryResult{}
+	for _, result := range against.Results {
+		againstByLabels[canonicalMetricKey(result.Metric)] = result
+	}
+
+	var failures []SeriesReport
+	for _, result := range qrs.Results {
+		labels := canonicalMetricKey(result.Metric)
+		pair, ok := againstByLabels[labels]
+		if !ok {
+			continue
+		}
+
+		againstByTimestamp := map[float64]float64{}
+		for _, v := range pair.Values {
+			againstByTimestamp[v.Timestamp] = v.Value
+		}
+
+		for _, v := range result.Values {
+			againstValue, ok := againstByTimestamp[v.Timestamp]
+			if !ok {
+				continue
+			}
+
+			relErr := relativeError(v.Value, againstValue)
+			if relErr > exp.Tolerance {
+				failures = append(failures, SeriesReport{
+					Expectation:   exp.Name,
+					Labels:        labels,
+					Timestamp:     v.Timestamp,
+					Value:         v.Value,
+					AgainstValue:  againstValue,
+					RelativeError: relErr,
+				})
+			}
+		}
+	}
+
+	return failures
+}
+
+// relativeError returns |a-b| / max(|a|,|b|), treating two zero values as
+// exactly equal.
+func relativeError(a, b float64) float64 {
+	denom := math.Max(math.Abs(a), math.Abs(b))
+	if denom == 0 {
+		return 0
+	}
+	return math.Abs(a-b) / denom
+}
@@ -0,0 +1,68 @@
+package prom
+
+import (
+	"testing"
+
+	"github.com/kubecost/cost-model/pkg/util"
+)
+
+// buildMetric returns a metric map with the same four labels built up in a
+// different key order each time it's called, mimicking two independently
+// decoded JSON objects with identical content.
+func buildMetric(order int) map[string]interface{} {
+	labels := []string{"namespace", "pod", "container", "instance"}
+	values := map[string]string{
+		"namespace": "kube-system",
+		"pod":       "metrics-server-1",
+		"container": "metrics-server",
+		"instance":  "10.0.0.1:10250",
+	}
+
+	m := map[string]interface{}{}
+	for i := range labels {
+		k := labels[(i+order)%len(labels)]
+		m[k] = values[k]
+	}
+	return m
+}
+
+func TestCanonicalMetricKeyIsOrderIndependent(t *testing.T) {
+	var keys []string
+	for order := 0; order < 20; order++ {
+		keys = append(keys, canonicalMetricKey(buildMetric(order)))
+	}
+
+	for i, k := range keys {
+		if k != keys[0] {
+			t.Fatalf("canonicalMetricKey produced a different key at iteration %d: %q != %q", i, k, keys[0])
+		}
+	}
+}
+
+func TestCompareResultsJoinsAcrossIndependentlyBuiltMetrics(t *testing.T) {
+	exp := Expectation{Name: "memory-drift", Tolerance: 0.25}
+
+	qrs := &QueryResults{Results: []*QueryResult{
+		{
+			Metric: buildMetric(0),
+			Values: []*util.Vector{{Timestamp: 100, Value: 150}},
+		},
+	}}
+	against := &QueryResults{Results: []*QueryResult{
+		{
+			Metric: buildMetric(2),
+			Values: []*util.Vector{{Timestamp: 100, Value: 100}},
+		},
+	}}
+
+	// 150 vs. 100 is a 33% relative error, comfortably past the 25%
+	// tolerance. If the join key depended on map iteration order, this
+	// would flap between 0 and 1 failures across iterations instead of
+	// reliably finding the pair and reporting it every time.
+	for i := 0; i < 20; i++ {
+		failures := compareResults(exp, qrs, against)
+		if len(failures) != 1 {
+			t.Fatalf("iteration %d: expected the differently-ordered-but-identical metrics to join and report 1 failure, got %d", i, len(failures))
+		}
+	}
+}